@@ -0,0 +1,15 @@
+package pipeman
+
+// BatchEnqueuer enqueues several tasks in a single round-trip, for
+// backends that can pipeline writes (e.g. Redis MULTI, a SQL
+// transaction).
+type BatchEnqueuer interface {
+	EnqueueBatch(tasks []*Task, opts *EnqueueOptions) error
+}
+
+// BatchDequeuer dequeues up to max tasks in a single round-trip. It may
+// return fewer than max tasks, including zero, when the queue has fewer
+// ready tasks available.
+type BatchDequeuer interface {
+	DequeueBatch(max int, opts *DequeueOptions) ([]*Task, error)
+}
@@ -0,0 +1,81 @@
+package pipeman
+
+import (
+	"fmt"
+
+	jsoniter "github.com/json-iterator/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec marshals and unmarshals a Task's payload. Implementations should
+// be safe for concurrent use, since a single Codec is typically shared
+// across an Enqueuer or Dequeuer.
+type Codec interface {
+	// Marshal appends the encoded form of v to buf (which may be nil)
+	// and returns the resulting slice.
+	Marshal(buf []byte, v interface{}) ([]byte, error)
+	// Unmarshal decodes data into v.
+	Unmarshal(data []byte, v interface{}) error
+	// ContentType identifies the encoding, e.g. "application/json". It is
+	// stored on Task.Encoding so a worker can pick a matching codec.
+	ContentType() string
+}
+
+// DefaultCodec is used by Task.marshalPayload/unmarshalPayload when a
+// Task has no codec set via SetCodec.
+var DefaultCodec Codec = JSONCodec{}
+
+// JSONCodec encodes payloads as JSON using jsoniter. It is pipeman's
+// original, default payload encoding.
+type JSONCodec struct{}
+
+// Marshal implements Codec.
+func (JSONCodec) Marshal(buf []byte, v interface{}) ([]byte, error) {
+	b, err := jsoniter.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if buf == nil {
+		return b, nil
+	}
+	return append(buf, b...), nil
+}
+
+// Unmarshal implements Codec.
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return jsoniter.Unmarshal(data, v)
+}
+
+// ContentType implements Codec.
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+// ProtoCodec encodes payloads using google.golang.org/protobuf/proto.
+// Payloads passed to Marshal and Unmarshal must implement proto.Message.
+// Use it for large or strongly typed payloads where JSON's size and lack
+// of schema evolution become a problem.
+type ProtoCodec struct{}
+
+// Marshal implements Codec. v must implement proto.Message.
+func (ProtoCodec) Marshal(buf []byte, v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("pipeman: ProtoCodec: %T does not implement proto.Message", v)
+	}
+	return proto.MarshalOptions{}.MarshalAppend(buf, m)
+}
+
+// Unmarshal implements Codec. v must implement proto.Message.
+func (ProtoCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("pipeman: ProtoCodec: %T does not implement proto.Message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// ContentType implements Codec.
+func (ProtoCodec) ContentType() string {
+	return "application/x-protobuf"
+}
@@ -0,0 +1,39 @@
+package pipeman
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONCodec(t *testing.T) {
+	var codec JSONCodec
+	require.Equal(t, "application/json", codec.ContentType())
+
+	b, err := codec.Marshal(nil, product{Title: "product title"})
+	require.NoError(t, err)
+	require.Equal(t, `{"Title":"product title","Variants":null}`, string(b))
+
+	var p product
+	err = codec.Unmarshal(b, &p)
+	require.NoError(t, err)
+	require.Equal(t, "product title", p.Title)
+}
+
+func TestTask_unmarshalPayload_encodingMismatch(t *testing.T) {
+	task := NewTask()
+	task.Payload = []byte(`{"Title":"product title"}`)
+	task.Encoding = ProtoCodec{}.ContentType()
+
+	var p product
+	err := task.unmarshalPayload(&p)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "pipeman: invalid task payload")
+}
+
+func TestTask_marshalPayload_setsEncoding(t *testing.T) {
+	task := NewTask()
+	err := task.marshalPayload(product{Title: "product title"})
+	require.NoError(t, err)
+	require.Equal(t, JSONCodec{}.ContentType(), task.Encoding)
+}
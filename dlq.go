@@ -0,0 +1,16 @@
+package pipeman
+
+// DeadLetterQueue stores tasks that exhausted their retries, so operators
+// can inspect and replay them. Tasks moved here keep their original ID
+// and Payload alongside the terminal Error and FailedAt that sent them
+// there.
+type DeadLetterQueue interface {
+	// MoveToDLQ moves task to the dead-letter queue, recording reason as
+	// task.Error and stamping task.FailedAt.
+	MoveToDLQ(task *Task, reason string, opts *AckOptions) error
+	// ListDLQ returns the dead-lettered tasks in opts.Namespace.
+	ListDLQ(opts *FindOptions) ([]*Task, error)
+	// Requeue moves the dead-lettered task identified by taskID back onto
+	// its queue via opts, for a fresh set of attempts.
+	Requeue(taskID string, opts *EnqueueOptions) error
+}
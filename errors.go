@@ -0,0 +1,114 @@
+package pipeman
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// APIError is a structured, inspectable error returned by every
+// Validate() method and queue operation in this package. Compare errors
+// with IsCode (or errors.Is against one of the package's Err* values)
+// rather than with ==, since the Err cause and Details can differ
+// between two errors that share the same Code.
+type APIError struct {
+	// Code is a stable, dotted machine-readable identifier, e.g.
+	// "validation.empty_namespace" or "task.cancelled".
+	Code string
+	// HTTPStatusCode is the status an HTTP adapter should respond with.
+	HTTPStatusCode int
+	// Message is a human-readable description of the error.
+	Message string
+	// Details carries field-level context, e.g. {"field": "namespace"}.
+	Details map[string]any
+	// Err is the underlying cause, if any.
+	Err error
+}
+
+// Error implements error.
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("pipeman: %s: %v", e.Message, e.Err)
+	}
+	return fmt.Sprintf("pipeman: %s", e.Message)
+}
+
+// Unwrap lets errors.Is/errors.As reach e.Err.
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an *APIError with the same Code, so
+// errors.Is(err, ErrEmptyNamespace) keeps working as a sentinel-style
+// comparison even though ErrEmptyNamespace is now an *APIError.
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	if !ok {
+		return false
+	}
+	return e.Code == t.Code
+}
+
+// WithCause returns a copy of e with Err set to cause.
+func (e *APIError) WithCause(cause error) *APIError {
+	cp := *e
+	cp.Err = cause
+	return &cp
+}
+
+// WithDetails returns a copy of e with Details set.
+func (e *APIError) WithDetails(details map[string]any) *APIError {
+	cp := *e
+	cp.Details = details
+	return &cp
+}
+
+// IsCode reports whether err is, or wraps, an *APIError whose Code
+// equals code.
+func IsCode(err error, code string) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == code
+}
+
+// apiErrorBody is the wire shape APIError serializes to and parses from
+// over HTTP.
+type apiErrorBody struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// FromHTTPResponse reconstructs an *APIError from an HTTP response
+// written by (*APIError).WriteHTTP. If the body isn't a recognizable
+// error body, it returns a generic "http.error" APIError carrying the
+// response status instead.
+func FromHTTPResponse(resp *http.Response) *APIError {
+	defer resp.Body.Close()
+	var body apiErrorBody
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Code == "" {
+		return &APIError{
+			Code:           "http.error",
+			HTTPStatusCode: resp.StatusCode,
+			Message:        fmt.Sprintf("unexpected response: %s", resp.Status),
+			Err:            err,
+		}
+	}
+	return &APIError{
+		Code:           body.Code,
+		HTTPStatusCode: resp.StatusCode,
+		Message:        body.Message,
+		Details:        body.Details,
+	}
+}
+
+// WriteHTTP writes e to w as a JSON error body, with status
+// e.HTTPStatusCode.
+func (e *APIError) WriteHTTP(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.HTTPStatusCode)
+	_ = json.NewEncoder(w).Encode(apiErrorBody{Code: e.Code, Message: e.Message, Details: e.Details})
+}
@@ -0,0 +1,41 @@
+package pipeman
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIError_ErrorAndIs(t *testing.T) {
+	err := ErrEmptyNamespace
+	require.EqualError(t, err, "pipeman: empty namespace")
+	require.True(t, errors.Is(err, ErrEmptyNamespace))
+	require.False(t, errors.Is(err, ErrEmptyQname))
+
+	wrapped := ErrInvalidPayload.WithCause(errors.New("boom"))
+	require.True(t, errors.Is(wrapped, ErrInvalidPayload))
+	require.EqualError(t, wrapped, "pipeman: invalid task payload: boom")
+}
+
+func TestIsCode(t *testing.T) {
+	require.True(t, IsCode(ErrEmptyQname, "validation.empty_qname"))
+	require.False(t, IsCode(ErrEmptyQname, "validation.empty_namespace"))
+	require.False(t, IsCode(errors.New("plain"), "validation.empty_qname"))
+}
+
+func TestAPIError_WriteHTTPAndFromHTTPResponse(t *testing.T) {
+	rec := httptest.NewRecorder()
+	orig := ErrTaskNotFound.WithDetails(map[string]any{"task_id": "abc"})
+	orig.WriteHTTP(rec)
+
+	resp := rec.Result()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	got := FromHTTPResponse(resp)
+	require.Equal(t, orig.Code, got.Code)
+	require.Equal(t, orig.Message, got.Message)
+	require.Equal(t, "abc", got.Details["task_id"])
+}
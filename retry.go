@@ -0,0 +1,85 @@
+package pipeman
+
+import (
+	"math/rand"
+	"time"
+)
+
+// FailOptions specifies how a failed task is recorded and retried.
+type FailOptions struct {
+	// Namesapce is a data isolation space for each queue
+	Namespace string
+	// Qname is the name of a queue
+	Qname string
+	// Err is the error that caused the task to fail. Its message is
+	// stored on Task.Error.
+	Err error
+	// RetryPolicy controls whether and when the task is retried before
+	// being dead-lettered. A nil RetryPolicy falls back to
+	// DefaultRetryPolicy.
+	RetryPolicy *RetryPolicy
+}
+
+// Validate to check the validity of the FailOptions.
+func (opts *FailOptions) Validate() error {
+	if opts.Namespace == "" {
+		return ErrEmptyNamespace
+	}
+	if opts.Qname == "" {
+		return ErrEmptyQname
+	}
+	return nil
+}
+
+// RetryPolicy controls how many times a failed task is retried and how
+// long to wait between attempts.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of delivery attempts before the
+	// task is moved to the dead-letter queue. Zero means retry forever.
+	MaxAttempts int
+	// BaseDelay is the backoff applied after the first failed attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the exponential backoff computed from BaseDelay.
+	MaxDelay time.Duration
+	// Jitter is the fraction (0..1) of the computed delay randomly added
+	// or subtracted, to avoid retries from many tasks landing at once.
+	Jitter float64
+}
+
+// DefaultRetryPolicy is used by Dequeuer.Fail when FailOptions.RetryPolicy
+// is nil.
+var DefaultRetryPolicy = &RetryPolicy{
+	MaxAttempts: 25,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Minute,
+	Jitter:      0.2,
+}
+
+// NextBackoff returns how long to wait after the attempt-th failed
+// attempt (1-indexed) before retrying, applying exponential backoff
+// capped at MaxDelay and randomized by Jitter. NextBackoff(1) is
+// BaseDelay before jitter.
+func (p *RetryPolicy) NextBackoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := p.BaseDelay << uint(attempt-1)
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		delta := float64(delay) * p.Jitter
+		delay += time.Duration(delta*rand.Float64()*2 - delta)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return delay
+}
+
+// Exhausted reports whether attempt has used up every retry allowed by p.
+// A MaxAttempts of zero means unlimited retries, so Exhausted always
+// returns false in that case.
+func (p *RetryPolicy) Exhausted(attempt int) bool {
+	return p.MaxAttempts > 0 && attempt >= p.MaxAttempts
+}
@@ -0,0 +1,58 @@
+package pipeman
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailOptions_Validate(t *testing.T) {
+	opts := FailOptions{}
+	err := opts.Validate()
+	require.Error(t, err)
+	require.EqualError(t, err, "pipeman: empty namespace")
+
+	opts.Namespace = "ns1"
+	opts.Qname = "queue1"
+	err = opts.Validate()
+	require.NoError(t, err)
+}
+
+func TestRetryPolicy_NextBackoff(t *testing.T) {
+	p := &RetryPolicy{BaseDelay: time.Second, MaxDelay: 4 * time.Second}
+
+	d := p.NextBackoff(3)
+	require.LessOrEqual(t, d, p.MaxDelay)
+	require.GreaterOrEqual(t, d, time.Duration(0))
+}
+
+func TestRetryPolicy_Exhausted(t *testing.T) {
+	p := &RetryPolicy{MaxAttempts: 3}
+	require.False(t, p.Exhausted(1))
+	require.False(t, p.Exhausted(2))
+	require.True(t, p.Exhausted(3))
+
+	unlimited := &RetryPolicy{}
+	require.False(t, unlimited.Exhausted(1000))
+}
+
+func TestTask_EffectiveDeadline(t *testing.T) {
+	now := time.Now()
+
+	task := NewTask()
+	task.Timeout = 10 * time.Second
+	require.Equal(t, now.Add(10*time.Second), task.EffectiveDeadline(now, &DequeueOptions{}))
+
+	task2 := NewTask()
+	require.True(t, task2.EffectiveDeadline(now, &DequeueOptions{DefaultTimeout: 5 * time.Second}).Equal(now.Add(5*time.Second)))
+
+	task3 := NewTask()
+	deadline := now.Add(time.Hour)
+	task3.Deadline = deadline
+	task3.Timeout = time.Second
+	require.Equal(t, deadline, task3.EffectiveDeadline(now, &DequeueOptions{}))
+
+	task4 := NewTask()
+	require.True(t, task4.EffectiveDeadline(now, &DequeueOptions{}).IsZero())
+}
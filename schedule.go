@@ -0,0 +1,162 @@
+package pipeman
+
+import (
+	"container/heap"
+	"net/http"
+	"time"
+)
+
+// ErrTaskCancelled is returned by a Dequeuer's Ack when the task being
+// acknowledged was cancelled while in flight.
+var ErrTaskCancelled = &APIError{Code: "task.cancelled", HTTPStatusCode: http.StatusConflict, Message: "task cancelled"}
+
+// Scheduler schedules a task to become visible at a future time.
+type Scheduler interface {
+	Schedule(*Task, *ScheduleOptions) error
+}
+
+// ScheduleOptions specifies how a task is scheduled.
+type ScheduleOptions struct {
+	// Namesapce is a data isolation space for each queue
+	Namespace string
+	// Qname is the name of a queue
+	Qname string
+	// NotBefore overrides Task.ProcessAt when it is later, so a task can
+	// be held back further than its own ProcessAt without mutating it.
+	NotBefore time.Time
+}
+
+// Validate to check the validity of the ScheduleOptions.
+func (opts *ScheduleOptions) Validate() error {
+	if opts.Namespace == "" {
+		return ErrEmptyNamespace
+	}
+	if opts.Qname == "" {
+		return ErrEmptyQname
+	}
+	return nil
+}
+
+// Canceller cancels a pending, scheduled, or in-flight task by its
+// CancelID.
+type Canceller interface {
+	Cancel(cancelID string, opts *CancelOptions) error
+}
+
+// CancelOptions specifies how a task is cancelled.
+type CancelOptions struct {
+	// Namesapce is a data isolation space for each queue
+	Namespace string
+	// Qname is the name of a queue
+	Qname string
+}
+
+// Validate to check the validity of the CancelOptions.
+func (opts *CancelOptions) Validate() error {
+	if opts.Namespace == "" {
+		return ErrEmptyNamespace
+	}
+	if opts.Qname == "" {
+		return ErrEmptyQname
+	}
+	return nil
+}
+
+// ScheduleIndex orders the scheduled tasks of a single (namespace, qname)
+// pair by ProcessAt, and keeps a secondary index from CancelID to task so
+// a Canceller can remove a pending or scheduled task in O(log n) instead
+// of scanning. Backends should keep one ScheduleIndex per queue, persist
+// its contents to storage on shutdown, and reload it (via repeated Push
+// calls) on startup so restarts don't lose scheduled work.
+//
+// ScheduleIndex is not safe for concurrent use; callers must guard it
+// with whatever lock or transaction already protects the backend.
+type ScheduleIndex struct {
+	items      scheduleHeap
+	byCancelID map[string]*scheduleItem
+}
+
+// NewScheduleIndex returns an empty ScheduleIndex.
+func NewScheduleIndex() *ScheduleIndex {
+	return &ScheduleIndex{byCancelID: make(map[string]*scheduleItem)}
+}
+
+// Push adds task to the index, keyed by its ProcessAt and, if set, its
+// CancelID.
+func (s *ScheduleIndex) Push(task *Task) {
+	it := &scheduleItem{taskID: task.ID, cancelID: task.CancelID, processAt: task.ProcessAt}
+	heap.Push(&s.items, it)
+	if it.cancelID != "" {
+		s.byCancelID[it.cancelID] = it
+	}
+}
+
+// PopDue removes and returns the IDs of every scheduled task whose
+// ProcessAt is at or before at, in ProcessAt order. It returns nil when
+// no task is due yet.
+func (s *ScheduleIndex) PopDue(at time.Time) []string {
+	var due []string
+	for s.items.Len() > 0 && !s.items[0].processAt.After(at) {
+		it := heap.Pop(&s.items).(*scheduleItem)
+		if it.cancelID != "" {
+			delete(s.byCancelID, it.cancelID)
+		}
+		due = append(due, it.taskID)
+	}
+	return due
+}
+
+// RemoveByCancelID removes the pending or scheduled task registered under
+// cancelID, if any, and reports whether it found one.
+func (s *ScheduleIndex) RemoveByCancelID(cancelID string) (taskID string, ok bool) {
+	it, found := s.byCancelID[cancelID]
+	if !found {
+		return "", false
+	}
+	heap.Remove(&s.items, it.index)
+	delete(s.byCancelID, cancelID)
+	return it.taskID, true
+}
+
+// Len reports how many tasks are currently scheduled.
+func (s *ScheduleIndex) Len() int {
+	return s.items.Len()
+}
+
+// scheduleItem is one entry in a scheduleHeap.
+type scheduleItem struct {
+	taskID    string
+	cancelID  string
+	processAt time.Time
+	index     int
+}
+
+// scheduleHeap implements container/heap.Interface, ordering items by
+// processAt ascending.
+type scheduleHeap []*scheduleItem
+
+func (h scheduleHeap) Len() int { return len(h) }
+
+func (h scheduleHeap) Less(i, j int) bool { return h[i].processAt.Before(h[j].processAt) }
+
+func (h scheduleHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *scheduleHeap) Push(x interface{}) {
+	it := x.(*scheduleItem)
+	it.index = len(*h)
+	*h = append(*h, it)
+}
+
+func (h *scheduleHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	it.index = -1
+	*h = old[:n-1]
+	return it
+}
@@ -0,0 +1,76 @@
+package pipeman
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleOptions_Validate(t *testing.T) {
+	opts := ScheduleOptions{}
+	err := opts.Validate()
+	require.Error(t, err)
+	require.EqualError(t, err, "pipeman: empty namespace")
+
+	opts.Namespace = "ns1"
+	err = opts.Validate()
+	require.Error(t, err)
+	require.EqualError(t, err, "pipeman: empty qname")
+
+	opts.Qname = "queue1"
+	err = opts.Validate()
+	require.NoError(t, err)
+}
+
+func TestCancelOptions_Validate(t *testing.T) {
+	opts := CancelOptions{}
+	err := opts.Validate()
+	require.Error(t, err)
+	require.EqualError(t, err, "pipeman: empty namespace")
+
+	opts.Namespace = "ns1"
+	opts.Qname = "queue1"
+	err = opts.Validate()
+	require.NoError(t, err)
+}
+
+func TestScheduleIndex_PopDue(t *testing.T) {
+	idx := NewScheduleIndex()
+	now := time.Now()
+
+	task1 := NewTask()
+	task1.ProcessAt = now.Add(2 * time.Second)
+	task2 := NewTask()
+	task2.ProcessAt = now.Add(1 * time.Second)
+	task3 := NewTask()
+	task3.ProcessAt = now.Add(10 * time.Second)
+
+	idx.Push(task1)
+	idx.Push(task2)
+	idx.Push(task3)
+	require.Equal(t, 3, idx.Len())
+
+	due := idx.PopDue(now.Add(5 * time.Second))
+	require.Equal(t, []string{task2.ID, task1.ID}, due)
+	require.Equal(t, 1, idx.Len())
+
+	due = idx.PopDue(now.Add(5 * time.Second))
+	require.Nil(t, due)
+}
+
+func TestScheduleIndex_RemoveByCancelID(t *testing.T) {
+	idx := NewScheduleIndex()
+	task := NewTask()
+	task.ProcessAt = time.Now().Add(time.Minute)
+	task.CancelID = "cancel-1"
+	idx.Push(task)
+
+	taskID, ok := idx.RemoveByCancelID("cancel-1")
+	require.True(t, ok)
+	require.Equal(t, task.ID, taskID)
+	require.Equal(t, 0, idx.Len())
+
+	_, ok = idx.RemoveByCancelID("cancel-1")
+	require.False(t, ok)
+}
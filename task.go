@@ -1,60 +1,128 @@
 package pipeman
 
 import (
-	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/google/uuid"
-	jsoniter "github.com/json-iterator/go"
 )
 
 var (
 
 	// options validation errors
-	ErrEmptyNamespace = errors.New("pipeman: empty namespace")
-	ErrEmptyQname     = errors.New("pipeman: empty qname")
-	ErrAt             = errors.New("pipeman: at should not be zero")
-	ErrInvisibleSec   = errors.New("pipeman: invisible sec should be >= 0")
+	ErrEmptyNamespace = &APIError{Code: "validation.empty_namespace", HTTPStatusCode: http.StatusBadRequest, Message: "empty namespace"}
+	ErrEmptyQname     = &APIError{Code: "validation.empty_qname", HTTPStatusCode: http.StatusBadRequest, Message: "empty qname"}
+	ErrAt             = &APIError{Code: "validation.empty_at", HTTPStatusCode: http.StatusBadRequest, Message: "at should not be zero"}
+	ErrInvisibleSec   = &APIError{Code: "validation.invalid_invisible_sec", HTTPStatusCode: http.StatusBadRequest, Message: "invisible sec should be >= 0"}
+	ErrTimeout        = &APIError{Code: "validation.invalid_default_timeout", HTTPStatusCode: http.StatusBadRequest, Message: "default timeout should be >= 0"}
+
+	// ErrInvalidPayload is returned by Task.unmarshalPayload when the
+	// payload bytes can't be decoded, or when Task.Encoding doesn't match
+	// the codec in use.
+	ErrInvalidPayload = &APIError{Code: "payload.invalid", HTTPStatusCode: http.StatusBadRequest, Message: "invalid task payload"}
+
+	// ErrTaskNotFound is returned when a CancelID, taskID, or other task
+	// lookup key doesn't resolve to a known task.
+	ErrTaskNotFound = &APIError{Code: "task.not_found", HTTPStatusCode: http.StatusNotFound, Message: "task not found"}
 )
 
 // Task it describes information about the task itself.
 type Task struct {
-	ID         string    `json:"id"`
-	Payload    []byte    `json:"payload"`
+	ID      string `json:"id"`
+	Payload []byte `json:"payload"`
+	// Encoding is the ContentType() of the Codec used to produce Payload.
+	// It lets a worker pick a matching codec when a queue carries tasks
+	// written by producers using different codecs.
+	Encoding   string    `json:"encoding"`
 	Error      string    `json:"error"`
 	CreatedAt  time.Time `json:"created_at"`
 	EnqueuedAt time.Time `json:"enqueued_at"`
 	RetriedAt  time.Time `json:"retried_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
+	// ProcessAt is the earliest time this task may be dequeued. Zero
+	// means the task is immediately ready, as it always was before
+	// Scheduler existed.
+	ProcessAt time.Time `json:"process_at"`
+	// CancelID, when non-empty, lets a Canceller find and cancel this
+	// task before or during processing.
+	CancelID string `json:"cancel_id"`
+	// Timeout bounds how long a single attempt may run before it is
+	// considered lost. It is used to compute EffectiveDeadline when
+	// Deadline itself is zero.
+	Timeout time.Duration `json:"timeout"`
+	// Deadline, if set, is the absolute time after which the task should
+	// no longer be attempted, regardless of Timeout.
+	Deadline time.Time `json:"deadline"`
+	// Attempt counts how many times this task has been dequeued and
+	// failed. It is incremented by Dequeuer.Fail.
+	Attempt int `json:"attempt"`
+	// FailedAt is set when the task is moved to the dead-letter queue,
+	// recording when its last attempt failed for good.
+	FailedAt time.Time `json:"failed_at"`
+
+	// codec is the Codec used by marshalPayload/unmarshalPayload. It
+	// defaults to DefaultCodec when nil; set it with SetCodec.
+	codec Codec
 }
 
-// InvalidPayloadError it represents the payload decoding error.
-type InvalidPayloadError struct {
-	Err error
+// SetCodec overrides the Codec used to marshal and unmarshal t.Payload.
+// It is typically called once, right after NewTask, by producers or
+// workers that don't use DefaultCodec.
+func (t *Task) SetCodec(c Codec) {
+	t.codec = c
 }
 
-func (e *InvalidPayloadError) Error() string {
-	return fmt.Sprintf("pipeman: invalid task payload: %v", e.Err)
+func (t *Task) codecOrDefault() Codec {
+	if t.codec != nil {
+		return t.codec
+	}
+	return DefaultCodec
 }
 
 func (t *Task) unmarshalPayload(v interface{}) error {
-	err := jsoniter.Unmarshal(t.Payload, v)
-	if err != nil {
-		return &InvalidPayloadError{Err: err}
+	codec := t.codecOrDefault()
+	if t.Encoding != "" && t.Encoding != codec.ContentType() {
+		return ErrInvalidPayload.WithCause(fmt.Errorf("task encoded as %q, but codec is %q",
+			t.Encoding, codec.ContentType()))
+	}
+	if err := codec.Unmarshal(t.Payload, v); err != nil {
+		return ErrInvalidPayload.WithCause(err)
 	}
 	return nil
 }
 
 func (t *Task) marshalPayload(v interface{}) error {
-	b, err := jsoniter.Marshal(v)
+	codec := t.codecOrDefault()
+	b, err := codec.Marshal(nil, v)
 	if err != nil {
 		return err
 	}
 	t.Payload = b
+	t.Encoding = codec.ContentType()
 	return nil
 }
 
+// EffectiveDeadline returns the absolute time after which a worker
+// holding t should give up, given that it was dequeued at at with
+// opts.DefaultTimeout in effect. It returns t.Deadline if set;
+// otherwise at plus t.Timeout, falling back to opts.DefaultTimeout when
+// t.Timeout is zero. It returns the zero Time if none of the three
+// yields a deadline.
+func (t *Task) EffectiveDeadline(at time.Time, opts *DequeueOptions) time.Time {
+	if !t.Deadline.IsZero() {
+		return t.Deadline
+	}
+	timeout := t.Timeout
+	if timeout == 0 && opts != nil {
+		timeout = opts.DefaultTimeout
+	}
+	if timeout > 0 {
+		return at.Add(timeout)
+	}
+	return time.Time{}
+}
+
 func NewTask() *Task {
 	taskID := uuid.NewString()
 	now := time.Now().Truncate(time.Second)
@@ -77,6 +145,9 @@ type EnqueueOptions struct {
 	Namespace string
 	// Qname is the name of a queue
 	Qname string
+	// Codec, when set, is used to marshal the task's payload instead of
+	// DefaultCodec or a codec set with Task.SetCodec.
+	Codec Codec
 }
 
 // Validate to check the validity of the EnqueueOptions.
@@ -92,9 +163,20 @@ func (opts *EnqueueOptions) Validate() error {
 
 // Dequeuer dequeues a task.
 // If a task is processed successfully, call Ack() to delete the job.
+// If the task was cancelled via Canceller while in flight, Ack returns
+// ErrTaskCancelled instead.
 type Dequeuer interface {
 	Dequeue(*DequeueOptions) (*Task, error)
 	Ack(*Task, *AckOptions) error
+	// ExtendVisibility pushes back task's invisibility window by extra,
+	// without losing the caller's lease, so a long-running worker can
+	// keep a task it still holds from being redelivered.
+	ExtendVisibility(task *Task, extra time.Duration, opts *AckOptions) error
+	// Fail records err against task, increments its Attempt counter, and
+	// re-queues it per opts.RetryPolicy (or DefaultRetryPolicy). Once the
+	// policy's MaxAttempts is exhausted, Fail instead calls MoveToDLQ and
+	// sets task.FailedAt.
+	Fail(task *Task, opts *FailOptions) error
 }
 
 // DequeueOptions specifies how a task is dequeued.
@@ -109,6 +191,13 @@ type DequeueOptions struct {
 	// After the task is dequeued, no other dequeuer can see this task for a while.
 	// InvisibleSec controls how long this period is.
 	InvisibleSec int64
+	// Codec, when set, is used to unmarshal a dequeued task's payload
+	// when its Encoding does not match DefaultCodec.
+	Codec Codec
+	// DefaultTimeout is the Timeout applied when a dequeued task doesn't
+	// set its own, so Task.EffectiveDeadline always has something to work
+	// with. Zero means such tasks have no deadline.
+	DefaultTimeout time.Duration
 }
 
 // Validate to check the validity of the DequeueOptions.
@@ -125,6 +214,9 @@ func (opt *DequeueOptions) Validate() error {
 	if opt.InvisibleSec < 0 {
 		return ErrInvisibleSec
 	}
+	if opt.DefaultTimeout < 0 {
+		return ErrTimeout
+	}
 	return nil
 }
 
@@ -160,8 +252,10 @@ func (opts *FindOptions) Validate() error {
 	return nil
 }
 
-// Queue can enqueue and dequeue jobs.
+// Queue can enqueue, dequeue, schedule, and cancel jobs.
 type Queue interface {
 	Enqueuer
 	Dequeuer
+	Scheduler
+	Canceller
 }